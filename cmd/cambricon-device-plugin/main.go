@@ -0,0 +1,171 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+// Command cambricon-device-plugin initializes every accelerator driver
+// requested on --drivers and registers a Kubernetes device plugin gRPC
+// server for the cambricon.com/mlu driver. Other drivers are initialized
+// but get no gRPC server and are not registered with kubelet.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/metrics"
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/mlu"
+)
+
+const (
+	kubeletSocket = "/var/lib/kubelet/device-plugins/kubelet.sock"
+
+	// podResourcesSocket is bind-mounted into the plugin pod by kubelet so
+	// the metrics collector can attribute device usage to a pod/container.
+	podResourcesSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+	// cambricon.com/mlu mirrors the resource name
+	// pkg/accelerator/cambricon registers itself under: mlu.Server is
+	// cndev-specific (hot-plug watching, topology-aware allocation), so
+	// only the driver advertising this resource gets one today. Other
+	// accelerator.Driver implementations (e.g. ascend) are initialized and
+	// have their socket path computed like any other driver, but need
+	// their own Server before kubelet can allocate their devices.
+	cambriconResourceName = "cambricon.com/mlu"
+)
+
+func main() {
+	flag.Parse()
+
+	drivers, err := mlu.ParseDrivers(*mlu.Drivers)
+	if err != nil {
+		log.Fatalf("cambricon-device-plugin: %v", err)
+	}
+
+	for _, d := range drivers {
+		if err := d.Init(); err != nil {
+			log.Fatalf("cambricon-device-plugin: init %s: %v", d.ResourceName(), err)
+		}
+	}
+
+	served := false
+	for _, d := range drivers {
+		socket := mlu.SocketPath(d)
+		if d.ResourceName() != cambriconResourceName {
+			log.Printf("cambricon-device-plugin: %s has no Server implementation yet, skipping %s", d.ResourceName(), socket)
+			continue
+		}
+		if err := serve(d.ResourceName(), socket); err != nil {
+			log.Fatalf("cambricon-device-plugin: serve %s: %v", d.ResourceName(), err)
+		}
+		served = true
+	}
+
+	if !served {
+		log.Fatalf("cambricon-device-plugin: no driver in %q has a Server implementation", *mlu.Drivers)
+	}
+
+	if *mlu.MetricsAddr != "" {
+		go serveMetrics(*mlu.MetricsAddr)
+	}
+
+	select {}
+}
+
+// serveMetrics exposes per-MLU telemetry on addr until the process exits.
+// It's only reachable for the cambricon driver today, since pkg/metrics
+// reads devices through pkg/cndev directly rather than accelerator.Device.
+func serveMetrics(addr string) {
+	devices, err := cndev.AllDevices()
+	if err != nil {
+		log.Fatalf("cambricon-device-plugin: metrics: %v", err)
+	}
+	deviceList := make([]cndev.Device, 0, len(devices))
+	for _, d := range devices {
+		deviceList = append(deviceList, d)
+	}
+
+	resolver := metrics.NewPodResourcesResolver(podResourcesSocket)
+	collector := metrics.NewCollector(os.Getenv("NODE_NAME"), deviceList, resolver)
+	if err := metrics.Serve(addr, collector); err != nil {
+		log.Fatalf("cambricon-device-plugin: metrics: %v", err)
+	}
+}
+
+// serve registers an mlu.Server for resourceName on socket, starts serving
+// gRPC in a background goroutine, then tells kubelet about it over the
+// Registration API. It returns once registration is done.
+func serve(resourceName, socket string) error {
+	srv, err := mlu.NewServer(resourceName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+
+	grpcServer := grpc.NewServer()
+	pluginapi.RegisterDevicePluginServer(grpcServer, srv)
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Printf("cambricon-device-plugin: %s gRPC server exited: %v", resourceName, err)
+		}
+	}()
+
+	if err := registerWithKubelet(resourceName, socket); err != nil {
+		return err
+	}
+	log.Printf("cambricon-device-plugin: serving %s on %s", resourceName, socket)
+	return nil
+}
+
+// registerWithKubelet tells kubelet's Registration service about the gRPC
+// endpoint just opened, the handshake kubelet requires before it will call
+// ListAndWatch on a device plugin.
+func registerWithKubelet(resourceName, socket string) error {
+	conn, err := grpc.Dial(kubeletSocket, grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := pluginapi.NewRegistrationClient(conn)
+	_, err = client.Register(context.Background(), &pluginapi.RegisterRequest{
+		Version:      pluginapi.Version,
+		Endpoint:     socket[len("/var/lib/kubelet/device-plugins/"):],
+		ResourceName: resourceName,
+	})
+	return err
+}
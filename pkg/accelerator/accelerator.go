@@ -0,0 +1,58 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+// Package accelerator abstracts a vendor's accelerator SDK behind a common
+// Driver/Device pair, so a single device plugin process can advertise cards
+// from more than one vendor (e.g. Cambricon MLUs alongside Ascend NPUs)
+// while sharing the health, metrics, and topology code paths.
+package accelerator
+
+// Device is a single accelerator card, vendor-agnostic.
+type Device interface {
+	// UUID returns the device's stable identifier.
+	UUID() (string, error)
+	// Path returns the device node path to mount into a container.
+	Path() (string, error)
+	// Health reports whether the device is healthy.
+	Health() (bool, error)
+	// Memory returns the device's total and used onboard memory, in bytes.
+	Memory() (total uint64, used uint64, err error)
+	// Utilization returns board utilization as a percentage, 0-100.
+	Utilization() (uint, error)
+	// Processes returns the pids of processes using the device and, per
+	// pid, the amount of device memory they hold, in MB.
+	Processes() ([]uint, []uint64, error)
+}
+
+// Driver loads a vendor SDK and enumerates the cards it manages.
+type Driver interface {
+	// Init loads the vendor SDK. It must be called once before Count or
+	// Open.
+	Init() error
+	// Count returns the number of cards visible to the driver.
+	Count() (uint, error)
+	// Open returns the Device at idx, 0 <= idx < Count().
+	Open(idx uint) (Device, error)
+	// Release unloads the vendor SDK.
+	Release() error
+	// ResourceName is the Kubernetes extended resource name this driver's
+	// devices are advertised under, e.g. "cambricon.com/mlu".
+	ResourceName() string
+}
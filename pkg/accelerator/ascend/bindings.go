@@ -0,0 +1,156 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+// Package ascend binds Huawei's DCMI library (libdcmi.so / libascendcl.so)
+// following the same dlopen-at-runtime pattern pkg/cndev uses for
+// Cambricon's CNDEV, so neither library needs to be present at build time.
+package ascend
+
+// #cgo CFLAGS: -I ./
+// #cgo LDFLAGS: -ldl -Wl,--unresolved-symbols=ignore-in-object-files
+// #include "ascend_dl.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+const szProcs = 32
+
+type handle struct {
+	UUID   string
+	PATH   string
+	cardID C.int
+	devID  C.int
+}
+
+func errorString(ret C.int) error {
+	if ret == C.int(C.DCMI_OK) {
+		return nil
+	}
+	return fmt.Errorf("ascend: dcmi call failed with code %d", int(ret))
+}
+
+func ascendInit() error {
+	r := C.ascendInit_dl()
+	if r == C.DCMI_ERR_UNINITIALIZED {
+		return errors.New("could not load DCMI library")
+	}
+	return errorString(C.int(r))
+}
+
+func ascendRelease() error {
+	return errorString(C.int(C.ascendRelease_dl()))
+}
+
+// deviceGetCount enumerates every NPU across every card, flattening the
+// card/device hierarchy DCMI exposes into the single contiguous index the
+// rest of this package, and accelerator.Driver, expect.
+func deviceGetCount() (uint, error) {
+	var cardList [szProcs]C.int
+	var cardCount C.int
+	r := C.dcmi_get_card_num_list(&cardCount, &cardList[0], C.int(szProcs))
+	if err := errorString(r); err != nil {
+		return 0, err
+	}
+
+	var total uint
+	for i := 0; i < int(cardCount); i++ {
+		var devCount C.int
+		var devIDs [szProcs]C.int
+		if C.dcmi_get_device_id_in_card(cardList[i], &devIDs[0], &devCount) != 0 {
+			continue
+		}
+		total += uint(devCount)
+	}
+	return total, nil
+}
+
+func deviceGetHandleByIndex(idx uint) (handle, error) {
+	var h handle
+
+	var cardList [szProcs]C.int
+	var cardCount C.int
+	if r := C.dcmi_get_card_num_list(&cardCount, &cardList[0], C.int(szProcs)); r != 0 {
+		return h, errorString(r)
+	}
+
+	remaining := int(idx)
+	for i := 0; i < int(cardCount); i++ {
+		var devCount C.int
+		var devIDs [szProcs]C.int
+		if C.dcmi_get_device_id_in_card(cardList[i], &devIDs[0], &devCount) != 0 {
+			continue
+		}
+		if remaining >= int(devCount) {
+			remaining -= int(devCount)
+			continue
+		}
+
+		h = handle{
+			UUID:   fmt.Sprintf("NPU-%d-%d", int(cardList[i]), int(devIDs[remaining])),
+			PATH:   fmt.Sprintf("/dev/davinci%d", devIDs[remaining]),
+			cardID: cardList[i],
+			devID:  devIDs[remaining],
+		}
+		return h, nil
+	}
+
+	return h, fmt.Errorf("ascend: index %d out of range", idx)
+}
+
+func (h handle) deviceGetHealth() (bool, error) {
+	var healthy C.int
+	r := C.dcmi_get_device_health(h.cardID, h.devID, &healthy)
+	return healthy == 0, errorString(r)
+}
+
+func (h handle) deviceGetMemory() (total uint64, used uint64, err error) {
+	var mem C.dcmiMemoryInfo_t
+	r := C.dcmi_get_device_memory_info(h.cardID, h.devID, &mem)
+	return uint64(mem.total), uint64(mem.used), errorString(r)
+}
+
+func (h handle) deviceGetUtilization() (uint, error) {
+	var util C.uint
+	// 2 is DCMI's "AI Core" utilization type; other values address HBM,
+	// vector core, etc.
+	r := C.dcmi_get_device_utilization_rate(h.cardID, h.devID, 2, &util)
+	return uint(util), errorString(r)
+}
+
+func (h handle) deviceGetProcesses() ([]uint, []uint64, error) {
+	var procs [szProcs]C.dcmiProcessInfo_t
+	count := C.uint(szProcs)
+	r := C.dcmi_get_device_proc_info(h.cardID, h.devID, &procs[0], &count)
+	if err := errorString(r); err != nil {
+		return nil, nil, err
+	}
+
+	n := int(count)
+	pids := make([]uint, n)
+	mems := make([]uint64, n)
+	for i := 0; i < n; i++ {
+		pids[i] = uint(procs[i].pid)
+		mems[i] = uint64(procs[i].memUsed) / 1024 / 1024
+	}
+	return pids, mems, nil
+}
@@ -0,0 +1,96 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+// Package cambricon adapts pkg/cndev to the accelerator.Driver interface.
+package cambricon
+
+import (
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/accelerator"
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+)
+
+const resourceName = "cambricon.com/mlu"
+
+func init() {
+	accelerator.Register("cambricon", New)
+}
+
+// New returns an accelerator.Driver backed by pkg/cndev.
+func New() accelerator.Driver {
+	return &driver{}
+}
+
+type driver struct{}
+
+func (*driver) Init() error {
+	return cndev.Init()
+}
+
+func (*driver) Count() (uint, error) {
+	return cndev.DeviceCount()
+}
+
+func (*driver) Open(idx uint) (accelerator.Device, error) {
+	h, err := cndev.NewDeviceByIndex(idx)
+	if err != nil {
+		return nil, err
+	}
+	return device{h}, nil
+}
+
+func (*driver) Release() error {
+	return cndev.Release()
+}
+
+func (*driver) ResourceName() string {
+	return resourceName
+}
+
+// device adapts cndev.Device to accelerator.Device. It can't embed
+// cndev.Device directly: cndev.Device already exposes UUID/PATH as fields,
+// which would collide with the UUID()/Path() methods accelerator.Device
+// requires.
+type device struct {
+	h cndev.Device
+}
+
+func (d device) UUID() (string, error) {
+	return d.h.UUID, nil
+}
+
+func (d device) Path() (string, error) {
+	return d.h.PATH, nil
+}
+
+func (d device) Health() (bool, error) {
+	return d.h.Healthy(0)
+}
+
+func (d device) Memory() (total uint64, used uint64, err error) {
+	return d.h.Memory()
+}
+
+func (d device) Utilization() (uint, error) {
+	return d.h.Utilization()
+}
+
+func (d device) Processes() ([]uint, []uint64, error) {
+	return d.h.Processes()
+}
@@ -0,0 +1,54 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package accelerator
+
+import "fmt"
+
+// Factory builds a Driver for one vendor. Vendor packages register
+// themselves with Register from an init func so main only needs to import
+// them for side effects.
+type Factory func() Driver
+
+var factories = map[string]Factory{}
+
+// Register makes a vendor's driver available under name, e.g. "cambricon"
+// or "ascend". It panics on duplicate registration, the same as database/sql
+// drivers do, since that always indicates a build-time mistake.
+func Register(name string, factory Factory) {
+	if _, exists := factories[name]; exists {
+		panic(fmt.Sprintf("accelerator: driver %q already registered", name))
+	}
+	factories[name] = factory
+}
+
+// Select builds one Driver per requested vendor name, in order, e.g. for
+// --drivers=cambricon,ascend.
+func Select(names []string) ([]Driver, error) {
+	drivers := make([]Driver, 0, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("accelerator: unknown driver %q", name)
+		}
+		drivers = append(drivers, factory())
+	}
+	return drivers, nil
+}
@@ -68,6 +68,12 @@ func cndevInit() error {
 	return init_()
 }
 
+// Init loads the CNDEV library and must be called once before any device is
+// opened.
+func Init() error {
+	return cndevInit()
+}
+
 func release_() error {
 	r := C.cndevRelease_dl()
 	return errorString(r)
@@ -228,6 +234,186 @@ func processName(pid uint) (string, error) {
 	return strings.TrimSuffix(string(d), "\n"), err
 }
 
+// PCIeInfo describes a device's location on the PCIe bus.
+type PCIeInfo struct {
+	Domain   uint
+	Bus      uint
+	Device   uint
+	Function uint
+}
+
+// ECCCounts reports the accumulated correctable/uncorrectable ECC error
+// counts for a device since the driver was loaded.
+type ECCCounts struct {
+	SingleBitErrors uint64
+	DoubleBitErrors uint64
+}
+
+var cardNames = map[C.cndevNameEnum_t]string{
+	C.MLU100: "MLU100",
+	C.MLU270: "MLU270",
+	C.MLU220: "MLU220",
+	C.MLU290: "MLU290",
+}
+
+//cndevRet_t cndevGetVersionInfo(cndevVersionInfo_t *versionInfo, int devId);
+func (h handle) DriverVersion() (string, error) {
+	var ret C.cndevRet_t
+	var versionInfo C.cndevVersionInfo_t
+	versionInfo.version = C.int(VERSION)
+	devId := C.int(h.MINOR)
+	ret = C.cndevGetVersionInfo(&versionInfo, devId)
+	version := fmt.Sprintf("%d.%d.%d", uint(versionInfo.driverMajorVersion), uint(versionInfo.driverMinorVersion), uint(versionInfo.driverBuildVersion))
+	return version, errorString(ret)
+}
+
+// DeviceGetName returns the human-readable card model, e.g. "MLU270".
+func (h handle) DeviceGetName() (string, error) {
+	cardName, err := deviceGetCardName(h.MINOR)
+	if err != nil {
+		return "", err
+	}
+	name, ok := cardNames[cardName]
+	if !ok {
+		name = "Unknown"
+	}
+	return name, nil
+}
+
+//cndevRet_t cndevGetTemperatureInfo(cndevTemperatureInfo_t *temperatureInfo, int devId);
+func (h handle) DeviceGetTemperature() (board int, chip int, err error) {
+	var ret C.cndevRet_t
+	var temperatureInfo C.cndevTemperatureInfo_t
+	temperatureInfo.version = C.int(VERSION)
+	devId := C.int(h.MINOR)
+	ret = C.cndevGetTemperatureInfo(&temperatureInfo, devId)
+	board = int(temperatureInfo.boardTemp)
+	chip = int(temperatureInfo.chipTemp)
+	return board, chip, errorString(ret)
+}
+
+//cndevRet_t cndevGetPowerInfo(cndevPowerInfo_t *powerInfo, int devId);
+func (h handle) DeviceGetPowerUsage() (uint, error) {
+	var ret C.cndevRet_t
+	var powerInfo C.cndevPowerInfo_t
+	powerInfo.version = C.int(VERSION)
+	devId := C.int(h.MINOR)
+	ret = C.cndevGetPowerInfo(&powerInfo, devId)
+	return uint(powerInfo.usage), errorString(ret)
+}
+
+//cndevRet_t cndevGetPCIeInfo(cndevPCIeInfo_t *pcieInfo, int devId);
+func (h handle) DeviceGetPCIeInfo() (PCIeInfo, error) {
+	var ret C.cndevRet_t
+	var pcieInfo C.cndevPCIeInfo_t
+	pcieInfo.version = C.int(VERSION)
+	devId := C.int(h.MINOR)
+	ret = C.cndevGetPCIeInfo(&pcieInfo, devId)
+	info := PCIeInfo{
+		Domain:   uint(pcieInfo.domain),
+		Bus:      uint(pcieInfo.bus),
+		Device:   uint(pcieInfo.device),
+		Function: uint(pcieInfo.function),
+	}
+	return info, errorString(ret)
+}
+
+//cndevRet_t cndevGetECCInfo(cndevECCInfo_t *eccInfo, int devId);
+func (h handle) DeviceGetECCErrors() (ECCCounts, error) {
+	var ret C.cndevRet_t
+	var eccInfo C.cndevECCInfo_t
+	eccInfo.version = C.int(VERSION)
+	devId := C.int(h.MINOR)
+	ret = C.cndevGetECCInfo(&eccInfo, devId)
+	counts := ECCCounts{
+		SingleBitErrors: uint64(eccInfo.oneBitErrors),
+		DoubleBitErrors: uint64(eccInfo.multiBitErrors),
+	}
+	return counts, errorString(ret)
+}
+
+//cndevRet_t cndevGetProcessName(int devId, unsigned pid, char *name, unsigned length);
+func (h handle) DeviceGetProcessNameByPID(pid uint) (string, error) {
+	var ret C.cndevRet_t
+	var name [C.CNDEV_MAX_PROCESS_NAME]C.char
+	devId := C.int(h.MINOR)
+	ret = C.cndevGetProcessName(devId, C.uint(pid), &name[0], C.CNDEV_MAX_PROCESS_NAME)
+	if ret == C.CNDEV_ERROR_NOT_SUPPORTED {
+		// older cards/drivers can't resolve the name from the device, fall
+		// back to reading it out of procfs.
+		return processName(pid)
+	}
+	if ret != C.CNDEV_SUCCESS {
+		return "", errorString(ret)
+	}
+	return C.GoString(&name[0]), nil
+}
+
+// TopologyRelationship classifies how two MLUs on the same node are
+// connected, ordered from closest to farthest.
+type TopologyRelationship int
+
+const (
+	// TopologySelf is returned when comparing a device against itself.
+	TopologySelf TopologyRelationship = iota
+	// TopologyMLULink means the two cards are joined by a direct MLU-Link.
+	TopologyMLULink
+	// TopologySingleSwitch means the two cards sit behind the same PCIe
+	// switch.
+	TopologySingleSwitch
+	// TopologySameNUMA means the two cards share a NUMA node but not a
+	// PCIe switch.
+	TopologySameNUMA
+	// TopologyCrossNUMA means the two cards are on different NUMA nodes.
+	TopologyCrossNUMA
+)
+
+var topologyRelationships = map[C.cndevTopologyRelationshipEnum_t]TopologyRelationship{
+	C.CNDEV_TOPOLOGY_SELF:    TopologySelf,
+	C.CNDEV_TOPOLOGY_MLULINK: TopologyMLULink,
+	C.CNDEV_TOPOLOGY_SINGLE:  TopologySingleSwitch,
+	C.CNDEV_TOPOLOGY_NODE:    TopologySameNUMA,
+	C.CNDEV_TOPOLOGY_SYSTEM:  TopologyCrossNUMA,
+}
+
+//cndevRet_t cndevGetTopologyRelationship(cndevTopologyRelationship_t *topology, int devId, int peerId);
+func (h handle) DeviceGetTopology(peer handle) (TopologyRelationship, error) {
+	var ret C.cndevRet_t
+	var topology C.cndevTopologyRelationship_t
+	topology.version = C.int(VERSION)
+	ret = C.cndevGetTopologyRelationship(&topology, C.int(h.MINOR), C.int(peer.MINOR))
+	rel, ok := topologyRelationships[topology.relationship]
+	if !ok {
+		rel = TopologyCrossNUMA
+	}
+	return rel, errorString(ret)
+}
+
+//cndevRet_t cndevGetMLULinkStatus(cndevMLULinkStatus_t *status, int devId, int peerId);
+func (h handle) DeviceGetMLULinkPeers() ([]uint, error) {
+	var ret C.cndevRet_t
+	var peers []uint
+	count, err := deviceGetCount()
+	if err != nil {
+		return nil, err
+	}
+	for peerMinor := uint(0); peerMinor < count; peerMinor++ {
+		if peerMinor == h.MINOR {
+			continue
+		}
+		var status C.cndevMLULinkStatus_t
+		status.version = C.int(VERSION)
+		ret = C.cndevGetMLULinkStatus(&status, C.int(h.MINOR), C.int(peerMinor))
+		if ret != C.CNDEV_SUCCESS {
+			continue
+		}
+		if status.linked != 0 {
+			peers = append(peers, peerMinor)
+		}
+	}
+	return peers, nil
+}
+
 func uint64Ptr(c C.long) *uint64 {
 	i := uint64(c)
 	return &i
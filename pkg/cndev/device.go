@@ -0,0 +1,70 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package cndev
+
+// Device is the handle other packages (pkg/mlu, pkg/metrics) use to query a
+// single MLU. It is a type alias for handle so those packages can hold and
+// pass it around without reaching into cndev internals.
+type Device = handle
+
+// DeviceCount returns the number of MLUs visible to the driver.
+func DeviceCount() (uint, error) {
+	return deviceGetCount()
+}
+
+// NewDeviceByIndex opens the MLU at the given index, 0 <= idx < DeviceCount().
+func NewDeviceByIndex(idx uint) (Device, error) {
+	return deviceGetHandleByIndex(idx)
+}
+
+// Healthy reports whether cndevGetCardHealthState considers the device
+// healthy. delaySeconds mirrors the sampling delay cndev itself expects.
+func (h handle) Healthy(delaySeconds int) (bool, error) {
+	code, err := h.deviceHealthCheckState(delaySeconds)
+	if err != nil {
+		return false, err
+	}
+	return code == 0, nil
+}
+
+// Memory returns the device's total and used onboard memory, in bytes.
+func (h handle) Memory() (total uint64, used uint64, err error) {
+	totalMem, devMem, err := h.deviceGetMemoryInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+	return *totalMem, *devMem.Used, nil
+}
+
+// Utilization returns the board utilization as a percentage, 0-100.
+func (h handle) Utilization() (uint, error) {
+	u, err := h.deviceGetBoardUtilization()
+	if err != nil {
+		return 0, err
+	}
+	return *u, nil
+}
+
+// Processes returns the pids of processes using the device and, per pid,
+// the amount of device memory they hold, in MB.
+func (h handle) Processes() ([]uint, []uint64, error) {
+	return h.deviceProcessInfo()
+}
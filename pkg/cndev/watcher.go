@@ -0,0 +1,301 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package cndev
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// EventType identifies what happened to a device in a Watcher Event.
+type EventType int
+
+const (
+	// Added indicates a card became visible to the driver after the
+	// Watcher started.
+	Added EventType = iota
+	// Removed indicates a previously visible card disappeared, e.g. it was
+	// drained or failed.
+	Removed
+	// Changed indicates a previously visible card emitted a "change"
+	// uevent (typically a reset) and should be re-read.
+	Changed
+)
+
+// Event describes a single hot-plug occurrence.
+type Event struct {
+	Type   EventType
+	UUID   string
+	Device Device
+}
+
+// fallbackPollInterval is how often the Watcher re-enumerates devices when
+// it has no netlink socket, or in addition to it as a safety net for
+// uevents lost under load.
+const fallbackPollInterval = 5 * time.Second
+
+// rawUevent is the minimal information netlinkLoop extracts from a kobject
+// uevent payload before handing it off to run, the Watcher's single
+// goroutine that owns `known`.
+type rawUevent struct {
+	action  string
+	devpath string
+}
+
+// Watcher tracks cambricon devices being added, removed, or reset after the
+// plugin has already started, so ListAndWatch can react without requiring a
+// plugin restart.
+//
+// known is only ever read or written from the run goroutine: netlinkLoop
+// and pollLoop merely feed it rawUevent/ticker signals over channels, they
+// never touch known directly. This keeps every access single-threaded
+// without needing a mutex.
+type Watcher struct {
+	events  chan Event
+	stop    chan struct{}
+	uevents chan rawUevent
+	known   map[string]Device
+	fd      int
+}
+
+// NewWatcher starts watching for hot-plug events. It opens an
+// AF_NETLINK/NETLINK_KOBJECT_UEVENT socket filtered to cambricon uevents,
+// falling back to polling deviceGetCount/deviceGetHandleByIndex alone on
+// kernels or sandboxes where that socket isn't available.
+func NewWatcher() (*Watcher, error) {
+	known, err := snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		events:  make(chan Event),
+		stop:    make(chan struct{}),
+		uevents: make(chan rawUevent),
+		known:   known,
+		fd:      -1,
+	}
+
+	if fd, err := openUeventSocket(); err == nil {
+		w.fd = fd
+		go w.netlinkLoop(fd)
+	}
+	go w.run()
+
+	return w, nil
+}
+
+// Events returns the channel hot-plug events are delivered on.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Close stops the Watcher's background goroutines. Closing the netlink fd
+// (rather than relying on a stop signal alone) is what unblocks
+// netlinkLoop's in-flight unix.Recvfrom, which has no deadline of its own.
+func (w *Watcher) Close() {
+	close(w.stop)
+	if w.fd >= 0 {
+		unix.Close(w.fd)
+	}
+}
+
+// AllDevices enumerates every MLU currently visible to the driver, keyed by
+// UUID. It's the one-shot counterpart to NewWatcher: callers use it to seed
+// their initial device list, then apply Watcher.Events() incrementally from
+// there.
+func AllDevices() (map[string]Device, error) {
+	return snapshot()
+}
+
+func snapshot() (map[string]Device, error) {
+	count, err := deviceGetCount()
+	if err != nil {
+		return nil, err
+	}
+	devices := make(map[string]Device, count)
+	for i := uint(0); i < count; i++ {
+		h, err := deviceGetHandleByIndex(i)
+		if err != nil {
+			continue
+		}
+		devices[h.UUID] = h
+	}
+	return devices, nil
+}
+
+func openUeventSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return -1, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1}); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// netlinkLoop only parses uevent payloads and forwards cambricon ones to
+// w.uevents; it never reads or writes w.known itself. Close() closes fd out
+// from under the blocked unix.Recvfrom to stop this loop, so every send to
+// w.uevents also watches w.stop to avoid leaking the goroutine if run has
+// already exited.
+func (w *Watcher) netlinkLoop(fd int) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return
+		}
+		ev, ok := parseUevent(buf[:n])
+		if !ok {
+			continue
+		}
+		select {
+		case w.uevents <- ev:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// parseUevent extracts the action and device path from a single kobject
+// uevent payload - a NUL-separated "ACTION@DEVPATH" header followed by
+// "KEY=VALUE" pairs - and reports ok=false for anything that isn't a
+// cambricon device node.
+func parseUevent(payload []byte) (rawUevent, bool) {
+	fields := bytes.Split(payload, []byte{0})
+	if len(fields) == 0 {
+		return rawUevent{}, false
+	}
+
+	parts := strings.SplitN(string(fields[0]), "@", 2)
+	if len(parts) != 2 {
+		return rawUevent{}, false
+	}
+	action, devpath := parts[0], parts[1]
+
+	var subsystem string
+	for _, f := range fields[1:] {
+		if kv := strings.SplitN(string(f), "=", 2); len(kv) == 2 && kv[0] == "SUBSYSTEM" {
+			subsystem = kv[1]
+		}
+	}
+	if subsystem != "cambricon" && !strings.Contains(devpath, "cambricon_dev") {
+		return rawUevent{}, false
+	}
+
+	return rawUevent{action: action, devpath: devpath}, true
+}
+
+func minorFromDevpath(devpath string) (uint, bool) {
+	const marker = "cambricon_dev"
+	i := strings.LastIndex(devpath, marker)
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(devpath[i+len(marker):], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(n), true
+}
+
+// run is the sole owner of w.known: it's the only goroutine that ever reads
+// or writes it, driven by uevents from netlinkLoop and fallbackPollInterval
+// ticks alike, so no lock is needed around it.
+func (w *Watcher) run() {
+	ticker := time.NewTicker(fallbackPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.reconcile()
+		case ev := <-w.uevents:
+			switch ev.action {
+			case "add", "remove":
+				w.reconcile()
+			case "change":
+				w.handleChange(ev.devpath)
+			}
+		}
+	}
+}
+
+func (w *Watcher) handleChange(devpath string) {
+	minor, ok := minorFromDevpath(devpath)
+	if !ok {
+		w.reconcile()
+		return
+	}
+	h, err := deviceGetHandleByIndex(minor)
+	if err != nil {
+		w.reconcile()
+		return
+	}
+	w.known[h.UUID] = h
+	w.emit(Event{Type: Changed, UUID: h.UUID, Device: h})
+}
+
+// reconcile re-enumerates every device and emits Added/Removed for whatever
+// differs from the last known snapshot. Only ever called from run.
+func (w *Watcher) reconcile() {
+	current, err := snapshot()
+	if err != nil {
+		return
+	}
+
+	for uuid, h := range current {
+		if _, ok := w.known[uuid]; !ok {
+			if !w.emit(Event{Type: Added, UUID: uuid, Device: h}) {
+				return
+			}
+		}
+	}
+	for uuid, h := range w.known {
+		if _, ok := current[uuid]; !ok {
+			if !w.emit(Event{Type: Removed, UUID: uuid, Device: h}) {
+				return
+			}
+		}
+	}
+	w.known = current
+}
+
+// emit delivers ev to Events(), but gives up and reports false the moment
+// Close is called instead of blocking forever on a consumer that has
+// stopped draining the channel - otherwise a slow or already-gone consumer
+// would wedge run() outside its own select loop and leak it past Close().
+func (w *Watcher) emit(ev Event) bool {
+	select {
+	case w.events <- ev:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
@@ -0,0 +1,108 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package cndev
+
+import (
+	"strings"
+	"testing"
+)
+
+func uevent(fields ...string) []byte {
+	return []byte(strings.Join(fields, "\x00") + "\x00")
+}
+
+func TestParseUevent(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    []byte
+		wantOK     bool
+		wantAction string
+		wantPath   string
+	}{
+		{
+			name:       "add by SUBSYSTEM",
+			payload:    uevent("add@/devices/pci0000:00/0000:00:01.0", "ACTION=add", "SUBSYSTEM=cambricon"),
+			wantOK:     true,
+			wantAction: "add",
+			wantPath:   "/devices/pci0000:00/0000:00:01.0",
+		},
+		{
+			name:       "change by devpath naming cambricon_dev",
+			payload:    uevent("change@/devices/virtual/cambricon_dev0", "ACTION=change"),
+			wantOK:     true,
+			wantAction: "change",
+			wantPath:   "/devices/virtual/cambricon_dev0",
+		},
+		{
+			name:    "unrelated subsystem is ignored",
+			payload: uevent("add@/devices/pci0000:00/0000:00:02.0", "ACTION=add", "SUBSYSTEM=net"),
+			wantOK:  false,
+		},
+		{
+			name:    "missing DEVPATH separator is ignored",
+			payload: uevent("add", "ACTION=add", "SUBSYSTEM=cambricon"),
+			wantOK:  false,
+		},
+		{
+			name:    "empty payload is ignored",
+			payload: []byte{},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseUevent(tt.payload)
+			if ok != tt.wantOK {
+				t.Fatalf("parseUevent() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.action != tt.wantAction || got.devpath != tt.wantPath {
+				t.Errorf("parseUevent() = %+v, want action=%q devpath=%q", got, tt.wantAction, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestMinorFromDevpath(t *testing.T) {
+	tests := []struct {
+		devpath   string
+		wantMinor uint
+		wantOK    bool
+	}{
+		{"/devices/virtual/cambricon_dev0", 0, true},
+		{"/devices/virtual/cambricon_dev12", 12, true},
+		{"/devices/virtual/other0", 0, false},
+	}
+
+	for _, tt := range tests {
+		minor, ok := minorFromDevpath(tt.devpath)
+		if ok != tt.wantOK {
+			t.Errorf("minorFromDevpath(%q) ok = %v, want %v", tt.devpath, ok, tt.wantOK)
+			continue
+		}
+		if ok && minor != tt.wantMinor {
+			t.Errorf("minorFromDevpath(%q) = %d, want %d", tt.devpath, minor, tt.wantMinor)
+		}
+	}
+}
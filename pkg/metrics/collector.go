@@ -0,0 +1,230 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+// Package metrics exports per-MLU telemetry, gathered through pkg/cndev, as
+// Prometheus metrics so the device plugin pod can be scraped directly
+// instead of requiring a sidecar exporter.
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/mlu"
+)
+
+const namespace = "mlu"
+
+var (
+	memoryUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_used_bytes"),
+		"Bytes of device memory currently in use.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	memoryTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_total_bytes"),
+		"Total bytes of device memory.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	boardUtilizationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "board_utilization"),
+		"Board utilization percentage, 0-100.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	healthStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "health_state"),
+		"1 if the device is healthy, 0 otherwise.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	processMemoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "process_memory_bytes"),
+		"Bytes of device memory held by a single process.",
+		[]string{"uuid", "minor", "node", "pid", "process", "pod_namespace", "pod_name", "container"}, nil,
+	)
+	temperatureDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "temperature_celsius"),
+		"Board temperature in degrees Celsius.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	powerUsageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "power_usage_watts"),
+		"Board power draw in watts.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	eccDoubleBitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "ecc_double_bit_errors_total"),
+		"Cumulative uncorrectable ECC errors.",
+		[]string{"uuid", "minor", "node"}, nil,
+	)
+	infoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "info"),
+		"Static device info, value is always 1.",
+		[]string{"uuid", "minor", "node", "model", "driver_version", "pci_bus_id"}, nil,
+	)
+)
+
+// DeviceAssignment is the pod/container a device UUID is currently
+// allocated to.
+type DeviceAssignment struct {
+	Namespace string
+	Pod       string
+	Container string
+}
+
+// ProcessResolver maps device UUIDs to the pod/container they're allocated
+// to, typically backed by the kubelet PodResources API. The PodResources
+// API is keyed by device, not pid, so every process a device reports is
+// attributed to whichever container the device itself was allocated to.
+//
+// Snapshot is called once per Collect, not once per process: the
+// PodResources API only supports listing every allocation at once, so
+// Collect must take a single snapshot up front and look devices up against it
+// rather than round-tripping to kubelet per process.
+type ProcessResolver interface {
+	Snapshot() map[string]DeviceAssignment
+}
+
+// Collector implements prometheus.Collector over the MLUs visible through
+// pkg/cndev.
+type Collector struct {
+	node     string
+	devices  []cndev.Device
+	resolver ProcessResolver
+}
+
+// NewCollector builds a Collector for the given devices. node is the
+// Kubernetes node name to label every metric with. resolver may be nil, in
+// which case pod_namespace/pod_name/container are left empty.
+func NewCollector(node string, devices []cndev.Device, resolver ProcessResolver) *Collector {
+	return &Collector{node: node, devices: devices, resolver: resolver}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- memoryUsedDesc
+	ch <- memoryTotalDesc
+	ch <- boardUtilizationDesc
+	ch <- healthStateDesc
+	ch <- processMemoryDesc
+	ch <- temperatureDesc
+	ch <- powerUsageDesc
+	ch <- eccDoubleBitDesc
+	ch <- infoDesc
+}
+
+// Collect implements prometheus.Collector. A device that fails to report a
+// given metric is skipped for that metric rather than failing the whole
+// scrape.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var assignments map[string]DeviceAssignment
+	if c.resolver != nil {
+		// One Snapshot, and therefore one dial plus one List call, serves
+		// every device and process in this scrape.
+		assignments = c.resolver.Snapshot()
+	}
+
+	for _, d := range c.devices {
+		minor := strconv.FormatUint(uint64(d.MINOR), 10)
+		labels := []string{d.UUID, minor, c.node}
+
+		if total, used, err := d.Memory(); err == nil {
+			ch <- prometheus.MustNewConstMetric(memoryTotalDesc, prometheus.GaugeValue, float64(total), labels...)
+			ch <- prometheus.MustNewConstMetric(memoryUsedDesc, prometheus.GaugeValue, float64(used), labels...)
+		}
+
+		if u, err := d.Utilization(); err == nil {
+			ch <- prometheus.MustNewConstMetric(boardUtilizationDesc, prometheus.GaugeValue, float64(u), labels...)
+		}
+
+		boardTemp, chipTemp, tempErr := d.DeviceGetTemperature()
+		if tempErr == nil {
+			ch <- prometheus.MustNewConstMetric(temperatureDesc, prometheus.GaugeValue, float64(boardTemp), labels...)
+		}
+
+		ecc, eccErr := d.DeviceGetECCErrors()
+		if eccErr == nil {
+			ch <- prometheus.MustNewConstMetric(eccDoubleBitDesc, prometheus.CounterValue, float64(ecc.DoubleBitErrors), labels...)
+		}
+
+		// mlu_health_state folds in temperature and ECC, not just cndev's
+		// own binary health state, the same signal ListAndWatch reports to
+		// kubelet via mlu.EvaluateHealth.
+		if healthy, err := d.Healthy(0); err == nil {
+			v := 0.0
+			if tempErr == nil && eccErr == nil && mlu.EvaluateHealth(healthy, boardTemp, chipTemp, ecc) == pluginapi.Healthy {
+				v = 1.0
+			}
+			ch <- prometheus.MustNewConstMetric(healthStateDesc, prometheus.GaugeValue, v, labels...)
+		}
+
+		if power, err := d.DeviceGetPowerUsage(); err == nil {
+			ch <- prometheus.MustNewConstMetric(powerUsageDesc, prometheus.GaugeValue, float64(power), labels...)
+		}
+
+		c.collectInfo(ch, d, labels)
+		c.collectProcesses(ch, d, labels, assignments)
+	}
+}
+
+func (c *Collector) collectInfo(ch chan<- prometheus.Metric, d cndev.Device, labels []string) {
+	model, err := d.DeviceGetName()
+	if err != nil {
+		return
+	}
+	driverVersion, err := d.DriverVersion()
+	if err != nil {
+		return
+	}
+	pcie, err := d.DeviceGetPCIeInfo()
+	if err != nil {
+		return
+	}
+	pciBusID := fmt.Sprintf("%04x:%02x:%02x.%x", pcie.Domain, pcie.Bus, pcie.Device, pcie.Function)
+
+	infoLabels := append(append([]string{}, labels...), model, driverVersion, pciBusID)
+	ch <- prometheus.MustNewConstMetric(infoDesc, prometheus.GaugeValue, 1, infoLabels...)
+}
+
+func (c *Collector) collectProcesses(ch chan<- prometheus.Metric, d cndev.Device, labels []string, assignments map[string]DeviceAssignment) {
+	pids, mems, err := d.Processes()
+	if err != nil {
+		return
+	}
+
+	assignment := assignments[d.UUID]
+	for i, pid := range pids {
+		if pid == 0 {
+			continue
+		}
+		name, err := d.DeviceGetProcessNameByPID(pid)
+		if err != nil {
+			name = ""
+		}
+		pidLabels := append(append([]string{}, labels...),
+			strconv.FormatUint(uint64(pid), 10), name, assignment.Namespace, assignment.Pod, assignment.Container)
+		// mems is reported in MB by pkg/cndev, convert to bytes to match the
+		// other memory gauges.
+		ch <- prometheus.MustNewConstMetric(processMemoryDesc, prometheus.GaugeValue, float64(mems[i]*1024*1024), pidLabels...)
+	}
+}
@@ -0,0 +1,114 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package metrics
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const podResourcesDialTimeout = 5 * time.Second
+
+// podResourcesResolver implements ProcessResolver on top of the kubelet
+// PodResources API socket. The gRPC connection is dialed once and reused
+// across every Snapshot call, rather than per device or per process, so a
+// scrape never does more than one List RPC.
+type podResourcesResolver struct {
+	socket string
+
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+}
+
+// NewPodResourcesResolver returns a ProcessResolver backed by the kubelet
+// PodResources gRPC API at socket (typically
+// /var/lib/kubelet/pod-resources/kubelet.sock, bind-mounted into the plugin
+// pod). It resolves to an empty snapshot if the socket can't be reached,
+// rather than failing the scrape.
+func NewPodResourcesResolver(socket string) ProcessResolver {
+	return &podResourcesResolver{socket: socket}
+}
+
+// Snapshot reuses the first successful connection to the kubelet
+// PodResources socket for the lifetime of the resolver (a failed dial is
+// tried again on the next call), and issues at most one List RPC per call,
+// however many devices or processes that call's Collect ends up labeling.
+func (r *podResourcesResolver) Snapshot() map[string]DeviceAssignment {
+	assignments := map[string]DeviceAssignment{}
+
+	conn, err := r.dial()
+	if err != nil {
+		return assignments
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), podResourcesDialTimeout)
+	defer cancel()
+
+	client := podresourcesapi.NewPodResourcesListerClient(conn)
+	resp, err := client.List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return assignments
+	}
+
+	for _, p := range resp.GetPodResources() {
+		for _, c := range p.GetContainers() {
+			for _, dev := range c.GetDevices() {
+				for _, id := range dev.GetDeviceIds() {
+					assignments[id] = DeviceAssignment{
+						Namespace: p.GetNamespace(),
+						Pod:       p.GetName(),
+						Container: c.GetName(),
+					}
+				}
+			}
+		}
+	}
+	return assignments
+}
+
+func (r *podResourcesResolver) dial() (*grpc.ClientConn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		return r.conn, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), podResourcesDialTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, r.socket, grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn = conn
+	return conn, nil
+}
@@ -0,0 +1,53 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/accelerator"
+
+	// Imported for their init() side effects, which register each vendor's
+	// driver with pkg/accelerator.
+	_ "github.com/Cambricon/cambricon-k8s-device-plugin/pkg/accelerator/ascend"
+	_ "github.com/Cambricon/cambricon-k8s-device-plugin/pkg/accelerator/cambricon"
+)
+
+const pluginSocketDir = "/var/lib/kubelet/device-plugins"
+
+// ParseDrivers splits the --drivers flag and resolves each name to its
+// accelerator.Driver.
+func ParseDrivers(flag string) ([]accelerator.Driver, error) {
+	names := strings.Split(flag, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return accelerator.Select(names)
+}
+
+// SocketPath returns the kubelet device plugin socket a driver's gRPC
+// server should listen on. Each driver gets its own socket and resource
+// name so kubelet treats them as independent device plugins.
+func SocketPath(d accelerator.Driver) string {
+	name := strings.ReplaceAll(d.ResourceName(), "/", "-")
+	return fmt.Sprintf("%s/%s.sock", pluginSocketDir, name)
+}
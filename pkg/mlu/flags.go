@@ -0,0 +1,33 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import "flag"
+
+// MetricsAddr is the listen address for the Prometheus /metrics endpoint,
+// e.g. ":8080". An empty value disables the exporter.
+var MetricsAddr = flag.String("metrics-addr", "", "address to serve Prometheus metrics on, empty disables it")
+
+// Drivers is the comma-separated list of accelerator vendors to serve,
+// e.g. "cambricon,ascend". Each one is initialized and has its own socket
+// path and Kubernetes extended resource name; only the cambricon driver is
+// served over gRPC and registered with kubelet.
+var Drivers = flag.String("drivers", "cambricon", "comma-separated accelerator drivers to serve, e.g. cambricon,ascend")
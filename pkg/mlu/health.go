@@ -0,0 +1,53 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import (
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+)
+
+const (
+	// maxBoardTempCelsius and maxChipTempCelsius are the thresholds above
+	// which a card is reported unhealthy to kubelet even when
+	// cndevGetCardHealthState itself still reports the device as healthy.
+	maxBoardTempCelsius = 95
+	maxChipTempCelsius  = 110
+)
+
+// EvaluateHealth folds the binary cndev health state together with the
+// richer telemetry surface (temperature, uncorrectable ECC errors) into the
+// single Healthy/Unhealthy signal ListAndWatch reports to kubelet. Also used
+// by pkg/metrics to decide mlu_health_state for the same reason: cndev's own
+// health state alone misses thermal and ECC failures.
+func EvaluateHealth(cndevHealthy bool, boardTemp, chipTemp int, ecc cndev.ECCCounts) string {
+	if !cndevHealthy {
+		return pluginapi.Unhealthy
+	}
+	if boardTemp > maxBoardTempCelsius || chipTemp > maxChipTempCelsius {
+		return pluginapi.Unhealthy
+	}
+	if ecc.DoubleBitErrors > 0 {
+		return pluginapi.Unhealthy
+	}
+	return pluginapi.Healthy
+}
@@ -0,0 +1,87 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import (
+	"testing"
+
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+)
+
+func TestEvaluateHealth(t *testing.T) {
+	tests := []struct {
+		name         string
+		cndevHealthy bool
+		boardTemp    int
+		chipTemp     int
+		ecc          cndev.ECCCounts
+		want         string
+	}{
+		{
+			name:         "cndev reports unhealthy",
+			cndevHealthy: false,
+			boardTemp:    50,
+			chipTemp:     60,
+			want:         pluginapi.Unhealthy,
+		},
+		{
+			name:         "board over temp",
+			cndevHealthy: true,
+			boardTemp:    maxBoardTempCelsius + 1,
+			chipTemp:     60,
+			want:         pluginapi.Unhealthy,
+		},
+		{
+			name:         "chip over temp",
+			cndevHealthy: true,
+			boardTemp:    50,
+			chipTemp:     maxChipTempCelsius + 1,
+			want:         pluginapi.Unhealthy,
+		},
+		{
+			name:         "uncorrectable ECC error",
+			cndevHealthy: true,
+			boardTemp:    50,
+			chipTemp:     60,
+			ecc:          cndev.ECCCounts{DoubleBitErrors: 1},
+			want:         pluginapi.Unhealthy,
+		},
+		{
+			name:         "healthy within thresholds",
+			cndevHealthy: true,
+			boardTemp:    maxBoardTempCelsius,
+			chipTemp:     maxChipTempCelsius,
+			ecc:          cndev.ECCCounts{SingleBitErrors: 3},
+			want:         pluginapi.Healthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateHealth(tt.cndevHealthy, tt.boardTemp, tt.chipTemp, tt.ecc)
+			if got != tt.want {
+				t.Errorf("EvaluateHealth() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
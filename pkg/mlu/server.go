@@ -0,0 +1,170 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import (
+	"context"
+	"fmt"
+
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/topology"
+)
+
+// Server implements pluginapi.DevicePluginServer for the cambricon.com/mlu
+// resource: it's what main registers against the gRPC socket at SocketPath,
+// and what kubelet actually calls ListAndWatch/Allocate/
+// GetPreferredAllocation on.
+type Server struct {
+	resourceName string
+	watcher      *cndev.Watcher
+	devices      map[string]cndev.Device
+	topology     *TopologyServer
+}
+
+// NewServer seeds the initial device list via cndev.AllDevices, builds the
+// topology.Graph GetPreferredAllocation will search, and starts a
+// cndev.Watcher so ListAndWatch can react to hot-plug events without a
+// plugin restart.
+func NewServer(resourceName string) (*Server, error) {
+	devices, err := cndev.AllDevices()
+	if err != nil {
+		return nil, err
+	}
+	deviceList := make([]cndev.Device, 0, len(devices))
+	for _, d := range devices {
+		deviceList = append(deviceList, d)
+	}
+	graph, err := topology.Build(deviceList)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := cndev.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		resourceName: resourceName,
+		watcher:      watcher,
+		devices:      devices,
+		topology:     NewTopologyServer(graph),
+	}, nil
+}
+
+// Close stops the underlying Watcher.
+func (s *Server) Close() {
+	s.watcher.Close()
+}
+
+// GetDevicePluginOptions implements pluginapi.DevicePluginServer.
+func (s *Server) GetDevicePluginOptions(ctx context.Context, e *pluginapi.Empty) (*pluginapi.DevicePluginOptions, error) {
+	return &pluginapi.DevicePluginOptions{GetPreferredAllocationAvailable: true}, nil
+}
+
+// ListAndWatch implements pluginapi.DevicePluginServer, sending kubelet the
+// current device list up front and again whenever the Watcher reports a
+// card added, removed, or reset.
+func (s *Server) ListAndWatch(e *pluginapi.Empty, stream pluginapi.DevicePlugin_ListAndWatchServer) error {
+	if err := stream.Send(&pluginapi.ListAndWatchResponse{Devices: s.pluginDevices()}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case ev, ok := <-s.watcher.Events():
+			if !ok {
+				return nil
+			}
+			s.devices = allocatableDevices(s.devices, ev)
+			if err := stream.Send(&pluginapi.ListAndWatchResponse{Devices: s.pluginDevices()}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// pluginDevices reports each device's health the same way pkg/metrics does:
+// cndev's own health bit folded together with temperature and ECC via
+// EvaluateHealth, not cndev's health bit alone.
+func (s *Server) pluginDevices() []*pluginapi.Device {
+	devices := make([]*pluginapi.Device, 0, len(s.devices))
+	for uuid, d := range s.devices {
+		devices = append(devices, &pluginapi.Device{
+			ID:     uuid,
+			Health: s.health(d),
+		})
+	}
+	return devices
+}
+
+func (s *Server) health(d cndev.Device) string {
+	healthy, err := d.Healthy(0)
+	if err != nil {
+		return pluginapi.Unhealthy
+	}
+	boardTemp, chipTemp, err := d.DeviceGetTemperature()
+	if err != nil {
+		return pluginapi.Unhealthy
+	}
+	ecc, err := d.DeviceGetECCErrors()
+	if err != nil {
+		return pluginapi.Unhealthy
+	}
+	return EvaluateHealth(healthy, boardTemp, chipTemp, ecc)
+}
+
+// Allocate implements pluginapi.DevicePluginServer, mounting the device
+// node for every requested ID into the container.
+func (s *Server) Allocate(ctx context.Context, req *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	resp := &pluginapi.AllocateResponse{}
+	for _, r := range req.ContainerRequests {
+		cresp := &pluginapi.ContainerAllocateResponse{}
+		for _, id := range r.DevicesIDs {
+			d, ok := s.devices[id]
+			if !ok {
+				return nil, fmt.Errorf("mlu: unknown device %q", id)
+			}
+			cresp.Devices = append(cresp.Devices, &pluginapi.DeviceSpec{
+				HostPath:      d.PATH,
+				ContainerPath: d.PATH,
+				Permissions:   "rw",
+			})
+		}
+		resp.ContainerResponses = append(resp.ContainerResponses, cresp)
+	}
+	return resp, nil
+}
+
+// PreStartContainer implements pluginapi.DevicePluginServer. MLUs need no
+// per-container setup, so this is a no-op.
+func (s *Server) PreStartContainer(ctx context.Context, req *pluginapi.PreStartContainerRequest) (*pluginapi.PreStartContainerResponse, error) {
+	return &pluginapi.PreStartContainerResponse{}, nil
+}
+
+// GetPreferredAllocation implements pluginapi.DevicePluginServer by
+// delegating to the topology.Graph built at startup, so multi-card
+// allocations favor whichever combination is best connected by MLU-Link.
+func (s *Server) GetPreferredAllocation(ctx context.Context, req *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
+	return s.topology.GetPreferredAllocation(ctx, req)
+}
@@ -0,0 +1,72 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import (
+	"context"
+
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/topology"
+)
+
+// topologyAnnotation is the node annotation the scheduler can read the raw
+// MLU link graph from, the same information GetPreferredAllocation below
+// uses to pick allocations.
+const topologyAnnotation = "cambricon.com/mlu-topology"
+
+// TopologyServer implements the topology-aware half of DevicePluginServer:
+// GetPreferredAllocation, plus the node annotation the scheduler can read
+// the same link graph from. It holds the topology.Graph built once at
+// startup rather than re-querying cndev per request.
+type TopologyServer struct {
+	graph *topology.Graph
+}
+
+// NewTopologyServer wraps an already-built topology.Graph.
+func NewTopologyServer(graph *topology.Graph) *TopologyServer {
+	return &TopologyServer{graph: graph}
+}
+
+// GetPreferredAllocation implements the DevicePluginServer method kubelet
+// calls before Allocate when it has more than one valid device combination
+// to choose from, returning whichever combination is best connected
+// according to the server's graph.
+func (s *TopologyServer) GetPreferredAllocation(ctx context.Context, req *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
+	resp := &pluginapi.PreferredAllocationResponse{}
+	for _, r := range req.ContainerRequests {
+		preferred := s.graph.PreferredAllocation(r.AvailableDeviceIDs, r.MustIncludeDeviceIDs, int(r.AllocationSize))
+		resp.ContainerResponses = append(resp.ContainerResponses, &pluginapi.ContainerPreferredAllocationResponse{
+			DeviceIDs: preferred,
+		})
+	}
+	return resp, nil
+}
+
+// NodeAnnotations returns the node annotations the plugin should apply so
+// the scheduler can see the same link graph GetPreferredAllocation uses.
+func (s *TopologyServer) NodeAnnotations() (map[string]string, error) {
+	raw, err := s.graph.Annotation()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{topologyAnnotation: raw}, nil
+}
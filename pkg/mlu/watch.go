@@ -0,0 +1,36 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package mlu
+
+import "github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+
+// allocatableDevices folds a cndev.Watcher event into a running set of
+// devices keyed by UUID, the shape ListAndWatch needs to re-send its device
+// list to kubelet whenever a card is hot-plugged, drained, or reset.
+func allocatableDevices(known map[string]cndev.Device, e cndev.Event) map[string]cndev.Device {
+	switch e.Type {
+	case cndev.Added, cndev.Changed:
+		known[e.UUID] = e.Device
+	case cndev.Removed:
+		delete(known, e.UUID)
+	}
+	return known
+}
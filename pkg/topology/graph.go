@@ -0,0 +1,245 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+// Package topology builds an undirected weighted graph of a node's MLUs
+// from pkg/cndev's inter-card link information, and picks the
+// best-connected subset of cards for a multi-card allocation.
+package topology
+
+import (
+	"encoding/json"
+
+	"github.com/Cambricon/cambricon-k8s-device-plugin/pkg/cndev"
+)
+
+// edgeWeight scores each cndev.TopologyRelationship, higher meaning closer and
+// more desirable for a multi-card allocation.
+var edgeWeight = map[cndev.TopologyRelationship]int{
+	cndev.TopologyMLULink:      4,
+	cndev.TopologySingleSwitch: 3,
+	cndev.TopologySameNUMA:     2,
+	cndev.TopologyCrossNUMA:    1,
+}
+
+// branchAndBoundLimit is the candidate-pool size above which
+// PreferredAllocation falls back to a greedy heaviest-edge extension
+// instead of exhaustive branch-and-bound.
+const branchAndBoundLimit = 16
+
+// Graph is an undirected weighted graph of a node's MLUs, keyed by UUID.
+type Graph struct {
+	uuids   []string
+	weights map[string]map[string]int
+}
+
+// Build queries cndev for the pairwise topology relationship between every
+// pair of devices and assembles the resulting graph. Pairs cndev also
+// reports as directly joined by an MLU-Link are pinned to
+// edgeWeight[cndev.TopologyMLULink] even if DeviceGetTopology's coarser
+// relationship would otherwise score them lower, since the MLU-Link fabric
+// is the fastest interconnect cndev exposes.
+func Build(devices []cndev.Device) (*Graph, error) {
+	g := &Graph{weights: make(map[string]map[string]int, len(devices))}
+	byMinor := make(map[uint]string, len(devices))
+	for _, d := range devices {
+		g.uuids = append(g.uuids, d.UUID)
+		g.weights[d.UUID] = map[string]int{}
+		byMinor[d.MINOR] = d.UUID
+	}
+
+	mluLinked := make(map[string]map[string]bool, len(devices))
+	for _, d := range devices {
+		peers, err := d.DeviceGetMLULinkPeers()
+		if err != nil {
+			continue
+		}
+		for _, peerMinor := range peers {
+			peerUUID, ok := byMinor[peerMinor]
+			if !ok {
+				continue
+			}
+			if mluLinked[d.UUID] == nil {
+				mluLinked[d.UUID] = map[string]bool{}
+			}
+			mluLinked[d.UUID][peerUUID] = true
+		}
+	}
+
+	for i, a := range devices {
+		for j := i + 1; j < len(devices); j++ {
+			b := devices[j]
+			rel, err := a.DeviceGetTopology(b)
+			if err != nil {
+				return nil, err
+			}
+			w := edgeWeight[rel]
+			if mluLinked[a.UUID][b.UUID] || mluLinked[b.UUID][a.UUID] {
+				w = edgeWeight[cndev.TopologyMLULink]
+			}
+			g.weights[a.UUID][b.UUID] = w
+			g.weights[b.UUID][a.UUID] = w
+		}
+	}
+	return g, nil
+}
+
+// UUIDs returns every device the graph knows about.
+func (g *Graph) UUIDs() []string {
+	return g.uuids
+}
+
+func (g *Graph) weight(a, b string) int {
+	if a == b {
+		return 0
+	}
+	return g.weights[a][b]
+}
+
+// cliqueWeight sums every pairwise edge weight within set.
+func (g *Graph) cliqueWeight(set []string) int {
+	total := 0
+	for i := range set {
+		for j := i + 1; j < len(set); j++ {
+			total += g.weight(set[i], set[j])
+		}
+	}
+	return total
+}
+
+// PreferredAllocation returns a size-sized subset of available that
+// includes every device in mustInclude, chosen to maximize the total
+// pairwise topology weight - the best-connected clique kubelet can hand to
+// a multi-card job. For candidate pools above branchAndBoundLimit it falls
+// back to a greedy heaviest-edge extension rather than exhaustive search.
+func (g *Graph) PreferredAllocation(available, mustInclude []string, size int) []string {
+	if size <= len(mustInclude) {
+		return append([]string{}, mustInclude[:size]...)
+	}
+
+	candidates := subtract(available, mustInclude)
+	want := size - len(mustInclude)
+	if want > len(candidates) {
+		want = len(candidates)
+	}
+
+	if len(candidates) > branchAndBoundLimit {
+		return greedyExtend(g, mustInclude, candidates, want)
+	}
+	return branchAndBound(g, mustInclude, candidates, want)
+}
+
+// Annotation serializes the graph's adjacency as JSON suitable for a node
+// annotation (e.g. cambricon.com/mlu-topology), so the scheduler can
+// consume the same link information the plugin used to pick allocations.
+func (g *Graph) Annotation() (string, error) {
+	b, err := json.Marshal(g.weights)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func subtract(all, exclude []string) []string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, e := range exclude {
+		excluded[e] = true
+	}
+	out := make([]string, 0, len(all))
+	for _, a := range all {
+		if !excluded[a] {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// greedyExtend repeatedly adds whichever remaining candidate is most
+// tightly linked to the devices already selected.
+func greedyExtend(g *Graph, selected, candidates []string, want int) []string {
+	chosen := append([]string{}, selected...)
+	remaining := append([]string{}, candidates...)
+
+	for i := 0; i < want && len(remaining) > 0; i++ {
+		bestIdx, bestWeight := 0, -1
+		for idx, c := range remaining {
+			w := 0
+			for _, s := range chosen {
+				w += g.weight(c, s)
+			}
+			if w > bestWeight {
+				bestIdx, bestWeight = idx, w
+			}
+		}
+		chosen = append(chosen, remaining[bestIdx])
+		remaining = append(remaining[:bestIdx], remaining[bestIdx+1:]...)
+	}
+	return chosen
+}
+
+// branchAndBound explores every way to pick `want` more devices out of
+// candidates to add to selected, pruning any partial choice whose best
+// possible completion can't beat the best complete choice found so far.
+// Realistic node sizes (<= branchAndBoundLimit cards) keep this tractable.
+func branchAndBound(g *Graph, selected, candidates []string, want int) []string {
+	bestSet := append(append([]string{}, selected...), candidates[:want]...)
+	bestWeight := g.cliqueWeight(bestSet)
+
+	var search func(chosen, pool []string)
+	search = func(chosen, pool []string) {
+		if len(chosen) == want {
+			full := append(append([]string{}, selected...), chosen...)
+			if w := g.cliqueWeight(full); w > bestWeight {
+				bestWeight = w
+				bestSet = full
+			}
+			return
+		}
+		if len(chosen)+len(pool) < want {
+			return
+		}
+		if upperBound(g, selected, chosen, want) <= bestWeight {
+			return
+		}
+
+		search(append(append([]string{}, chosen...), pool[0]), pool[1:])
+		search(chosen, pool[1:])
+	}
+
+	if want > 0 && len(candidates) > 0 {
+		search(nil, candidates)
+	}
+	return bestSet
+}
+
+// upperBound overestimates the best weight a branch could still reach: the
+// weight already locked in, plus every still-open slot pairing with
+// everything before it at the graph's maximum possible edge weight. It is
+// only ever used to decide whether to keep exploring a branch, never
+// reported as a result.
+func upperBound(g *Graph, selected, chosen []string, want int) int {
+	const maxEdgeWeight = 4
+
+	full := append(append([]string{}, selected...), chosen...)
+	bound := g.cliqueWeight(full)
+	for i, n := len(full), want-len(chosen); i < len(full)+n; i++ {
+		bound += i * maxEdgeWeight
+	}
+	return bound
+}
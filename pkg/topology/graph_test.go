@@ -0,0 +1,112 @@
+/*************************************************************************
+ * Copyright (C) [2019] by Cambricon, Inc. All rights reserved
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS
+ * OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+ * THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE.
+ *************************************************************************/
+
+package topology
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestGraph builds a Graph directly from a weight matrix, bypassing
+// Build/cndev so PreferredAllocation's search logic can be tested without
+// hardware.
+func newTestGraph(weights map[string]map[string]int) *Graph {
+	g := &Graph{weights: weights}
+	for uuid := range weights {
+		g.uuids = append(g.uuids, uuid)
+	}
+	sort.Strings(g.uuids)
+	return g
+}
+
+func symmetric(pairs map[[2]string]int) map[string]map[string]int {
+	w := map[string]map[string]int{}
+	ensure := func(u string) {
+		if w[u] == nil {
+			w[u] = map[string]int{}
+		}
+	}
+	for pair, weight := range pairs {
+		ensure(pair[0])
+		ensure(pair[1])
+		w[pair[0]][pair[1]] = weight
+		w[pair[1]][pair[0]] = weight
+	}
+	return w
+}
+
+func TestPreferredAllocationBranchAndBound(t *testing.T) {
+	// a-b and c-d are tightly linked pairs; a-c/a-d/b-c/b-d are weak. With a
+	// forced in by mustInclude, the best 2-of-4 pick is a's tight partner b,
+	// not the equally tight but unreachable {c,d} pair.
+	g := newTestGraph(symmetric(map[[2]string]int{
+		{"a", "b"}: 4,
+		{"c", "d"}: 4,
+		{"a", "c"}: 1,
+		{"a", "d"}: 1,
+		{"b", "c"}: 1,
+		{"b", "d"}: 1,
+	}))
+
+	got := g.PreferredAllocation([]string{"a", "b", "c", "d"}, []string{"a"}, 2)
+	sort.Strings(got)
+	want := []string{"a", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreferredAllocation() = %v, want %v", got, want)
+	}
+}
+
+func TestPreferredAllocationMustIncludeOnly(t *testing.T) {
+	g := newTestGraph(symmetric(map[[2]string]int{
+		{"a", "b"}: 4,
+	}))
+
+	got := g.PreferredAllocation([]string{"a", "b"}, []string{"a", "b"}, 1)
+	want := []string{"a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreferredAllocation() = %v, want %v", got, want)
+	}
+}
+
+func TestPreferredAllocationGreedyFallback(t *testing.T) {
+	// More candidates than branchAndBoundLimit forces the greedy path; the
+	// device most tightly linked to the mustInclude seed should still win.
+	weights := map[[2]string]int{}
+	uuids := make([]string, 0, branchAndBoundLimit+2)
+	seed := "seed"
+	uuids = append(uuids, seed)
+	for i := 0; i < branchAndBoundLimit+1; i++ {
+		id := string(rune('a' + i))
+		uuids = append(uuids, id)
+		weights[[2]string{seed, id}] = 1
+	}
+	best := string(rune('a' + branchAndBoundLimit))
+	weights[[2]string{seed, best}] = 4
+
+	g := newTestGraph(symmetric(weights))
+	got := g.PreferredAllocation(uuids, []string{seed}, 2)
+	sort.Strings(got)
+	want := []string{best, seed}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreferredAllocation() = %v, want %v", got, want)
+	}
+}